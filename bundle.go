@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TemplateBundle renders an entire directory tree of templates against a
+// single shared TemplateContext, the way Helm renders a chart's templates/
+// directory. Files whose base name starts with "_" are treated as
+// helpers-only: they are parsed alongside every other file as a partial (so
+// their `{{ define }}` blocks are reachable via `{{ template "name" . }}`)
+// but are never themselves rendered to output.
+type TemplateBundle struct {
+	dir       string
+	outputs   []string // relative output paths, in render order
+	templates map[string]*Template
+}
+
+// NewTemplateBundle recursively loads every template file under dir, in
+// deterministic shortest-path-first, then lexical, order.
+func NewTemplateBundle(dir string) (*TemplateBundle, error) {
+	var helpers, roots []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(filepath.Base(path), "_") {
+			helpers = append(helpers, path)
+		} else {
+			roots = append(roots, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outputFor := make(map[string]string, len(roots))
+	for _, root := range roots {
+		output, err := filepath.Rel(dir, root)
+		if err != nil {
+			return nil, err
+		}
+		outputFor[root] = output
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		a, b := outputFor[roots[i]], outputFor[roots[j]]
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	templates := make(map[string]*Template, len(roots))
+	outputs := make([]string, 0, len(roots))
+
+	for _, root := range roots {
+		output := outputFor[root]
+
+		tmpl, err := newTemplateSet(root, helpers, nil, output)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", output, err)
+		}
+
+		templates[output] = tmpl
+		outputs = append(outputs, output)
+	}
+
+	return &TemplateBundle{
+		dir:       dir,
+		outputs:   outputs,
+		templates: templates,
+	}, nil
+}
+
+// Outputs returns the relative output path of every non-helper file in the
+// bundle, in the deterministic shortest-path-first, then lexical, order
+// they will be rendered in.
+func (b *TemplateBundle) Outputs() []string {
+	return b.outputs
+}
+
+// Dependencies returns the deduplicated union of every file's Dependencies,
+// in the order the bundle will render them.
+func (b *TemplateBundle) Dependencies() []Dependency {
+	var deps []Dependency
+	seen := make(map[string]struct{})
+
+	for _, output := range b.outputs {
+		for _, d := range b.templates[output].Dependencies() {
+			if _, ok := seen[d.HashCode()]; !ok {
+				seen[d.HashCode()] = struct{}{}
+				deps = append(deps, d)
+			}
+		}
+	}
+
+	return deps
+}
+
+// Execute renders every non-helper file in the bundle against c, returning a
+// map of relative output path to rendered contents. It fails fast - on the
+// first file that errors (e.g. because c is missing data the file needs) -
+// with that file's path and line folded into the error.
+func (b *TemplateBundle) Execute(c *TemplateContext) (map[string][]byte, error) {
+	rendered := make(map[string][]byte, len(b.outputs))
+
+	for _, output := range b.outputs {
+		contents, err := b.templates[output].Execute(c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", output, err)
+		}
+		rendered[output] = contents
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBundleFile writes contents to dir/name, creating parent directories
+// as needed.
+func writeBundleFile(t *testing.T, dir, name string, contents string) string {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTemplateBundle_rendersInOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consul-template-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeBundleFile(t, dir, "_helpers.tpl", `{{ define "greeting" }}hello {{ key "name" }}{{ end }}`)
+	writeBundleFile(t, dir, "haproxy.cfg", `{{ template "greeting" . }}`)
+	writeBundleFile(t, dir, "sub/sidecar.conf", `{{ template "greeting" . }}`)
+
+	bundle, err := NewTemplateBundle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOutputs := []string{"haproxy.cfg", filepath.Join("sub", "sidecar.conf")}
+	if !reflect.DeepEqual(bundle.Outputs(), expectedOutputs) {
+		t.Fatalf("expected outputs %v, got %v", expectedOutputs, bundle.Outputs())
+	}
+
+	context := &TemplateContext{
+		Keys: map[string]string{"name": "world"},
+	}
+
+	rendered, err := bundle.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if num := len(rendered); num != 2 {
+		t.Fatalf("expected 2 rendered files, got: %d", num)
+	}
+
+	expected := []byte("hello world")
+	for output, contents := range rendered {
+		if !bytes.Equal(contents, expected) {
+			t.Errorf("%s: expected \n%q\n to equal \n%q\n", output, contents, expected)
+		}
+	}
+
+	if _, ok := rendered["_helpers.tpl"]; ok {
+		t.Error("expected helper file to not be rendered")
+	}
+}
+
+func TestTemplateBundle_sortsShortestPathFirstThenLex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consul-template-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// "z.conf" sorts after "sub/a.conf" lexically, but it's the shorter
+	// path, so it must win the comparator and render (and, here, fail)
+	// first.
+	writeBundleFile(t, dir, "z.conf", `{{ key "missing" }}`)
+	writeBundleFile(t, dir, "sub/a.conf", `{{ key "missing" }}`)
+
+	bundle, err := NewTemplateBundle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOutputs := []string{"z.conf", filepath.Join("sub", "a.conf")}
+	if !reflect.DeepEqual(bundle.Outputs(), expectedOutputs) {
+		t.Fatalf("expected outputs %v, got %v", expectedOutputs, bundle.Outputs())
+	}
+
+	_, err = bundle.Execute(&TemplateContext{})
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	if want := "z.conf:"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q to contain %q, got the error from the wrong file first", err.Error(), want)
+	}
+}
+
+func TestTemplateBundle_dependenciesDeduped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consul-template-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeBundleFile(t, dir, "_helpers.tpl", `{{ define "greeting" }}{{ key "name" }}{{ end }}`)
+	writeBundleFile(t, dir, "a.conf", `{{ template "greeting" . }}`)
+	writeBundleFile(t, dir, "b.conf", `{{ key "name" }}`)
+
+	bundle, err := NewTemplateBundle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dependencies := bundle.Dependencies()
+	if num := len(dependencies); num != 1 {
+		t.Fatalf("expected 1 deduplicated Dependency, got: %d", num)
+	}
+}
+
+func TestTemplateBundle_missingContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consul-template-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeBundleFile(t, dir, "a.conf", `{{ key "name" }}`)
+
+	bundle, err := NewTemplateBundle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = bundle.Execute(&TemplateContext{})
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	if want := "a.conf:"; !bytes.Contains([]byte(err.Error()), []byte(want)) {
+		t.Errorf("expected %q to contain %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+// TemplateContext is the result of fetching every Dependency a Template
+// asked for during its scan pass. This package does not fetch anything from
+// Consul itself: the caller builds one and hands it to Template.Execute
+// (tests build them by hand).
+type TemplateContext struct {
+	Services    map[string][]*Service
+	Keys        map[string]string
+	KeyPrefixes map[string][]*KeyPair
+
+	// Nodes is keyed by datacenter ("" for the local datacenter) and holds
+	// the result of a `nodes` catalog listing.
+	Nodes map[string][]*Node
+
+	// NodeDetails is keyed by node name and holds the result of a `node
+	// "<name>"` lookup.
+	NodeDetails map[string]*Node
+
+	// Datacenters holds the result of a `datacenters` lookup.
+	Datacenters []string
+}
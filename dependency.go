@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Dependency is something a Template needs resolved before it can be
+// rendered - a service, a key, a key prefix, etc. Templates discover their
+// Dependencies during a dependency-scan pass; fetching them from Consul is
+// left to the caller, which supplies the results in a TemplateContext.
+type Dependency interface {
+	// HashCode uniquely identifies this Dependency for deduplication.
+	HashCode() string
+
+	// Key is the raw string the dependency was parsed from, and the key
+	// used to look this Dependency's data up in a TemplateContext.
+	Key() string
+}
+
+// validServiceName is used to sanity-check the argument given to the
+// `service` template function before it is sent to Consul.
+var validServiceName = regexp.MustCompile(`\A[a-zA-Z0-9@:_\-\.]+\z`)
+
+// ServiceDependency is the representation of a requested service dependency
+// from inside a template.
+type ServiceDependency struct {
+	rawKey string
+}
+
+// ParseServiceDependency parses the given string into a ServiceDependency,
+// returning an error if the string does not look like a valid service name.
+func ParseServiceDependency(s string) (*ServiceDependency, error) {
+	if !validServiceName.MatchString(s) {
+		return nil, fmt.Errorf("invalid service dependency: %q", s)
+	}
+
+	return &ServiceDependency{rawKey: s}, nil
+}
+
+// Key returns the raw key the dependency was declared with.
+func (d *ServiceDependency) Key() string {
+	return d.rawKey
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *ServiceDependency) HashCode() string {
+	return fmt.Sprintf("ServiceDependency|%s", d.rawKey)
+}
+
+// KeyDependency is the representation of a requested key dependency from
+// inside a template.
+type KeyDependency struct {
+	rawKey string
+}
+
+// Key returns the raw key the dependency was declared with.
+func (d *KeyDependency) Key() string {
+	return d.rawKey
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *KeyDependency) HashCode() string {
+	return fmt.Sprintf("KeyDependency|%s", d.rawKey)
+}
+
+// KeyPrefixDependency is the representation of a requested key prefix
+// dependency from inside a template.
+type KeyPrefixDependency struct {
+	rawKey string
+}
+
+// Key returns the raw key the dependency was declared with.
+func (d *KeyPrefixDependency) Key() string {
+	return d.rawKey
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *KeyPrefixDependency) HashCode() string {
+	return fmt.Sprintf("KeyPrefixDependency|%s", d.rawKey)
+}
+
+// NodesDependency is the representation of a requested catalog node listing
+// from inside a template, optionally scoped to a single datacenter.
+type NodesDependency struct {
+	// rawKey is the datacenter to list nodes for, or "" for the agent's own
+	// datacenter.
+	rawKey string
+}
+
+// Key returns the datacenter this dependency is scoped to ("" for local).
+func (d *NodesDependency) Key() string {
+	return d.rawKey
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *NodesDependency) HashCode() string {
+	return fmt.Sprintf("NodesDependency|%s", d.rawKey)
+}
+
+// NodeDependency is the representation of a requested single-node detail
+// lookup from inside a template.
+type NodeDependency struct {
+	rawKey string
+}
+
+// Key returns the node name the dependency was declared with.
+func (d *NodeDependency) Key() string {
+	return d.rawKey
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *NodeDependency) HashCode() string {
+	return fmt.Sprintf("NodeDependency|%s", d.rawKey)
+}
+
+// DatacentersDependency is the representation of a requested listing of
+// known datacenters from inside a template.
+type DatacentersDependency struct{}
+
+// Key always returns "" - there is only ever one datacenters listing.
+func (d *DatacentersDependency) Key() string {
+	return ""
+}
+
+// HashCode returns a unique identifier for this dependency.
+func (d *DatacentersDependency) HashCode() string {
+	return "DatacentersDependency"
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Format identifies the structured format a rendered template is expected to
+// produce. Template.Execute uses it to decode the rendered bytes immediately
+// after rendering, so a broken config is caught here instead of by whatever
+// daemon consul-template hands it to.
+type Format int
+
+const (
+	// FormatNone performs no post-render validation. This is the default.
+	FormatNone Format = iota
+	FormatTOML
+	FormatYAML
+	FormatJSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatTOML:
+		return "TOML"
+	case FormatYAML:
+		return "YAML"
+	case FormatJSON:
+		return "JSON"
+	default:
+		return "none"
+	}
+}
+
+// ExecuteOption customizes a single Template.Execute call.
+type ExecuteOption func(*executeOptions)
+
+type executeOptions struct {
+	format Format
+}
+
+// WithFormat causes Execute to decode its rendered output as f immediately
+// after rendering, returning a decode error (with the offending line and a
+// few lines of surrounding context from the rendered output) instead of
+// silently handing back malformed content.
+func WithFormat(f Format) ExecuteOption {
+	return func(o *executeOptions) {
+		o.format = f
+	}
+}
+
+// validateFormat decodes rendered as format, returning a line- and
+// context-qualified error if it doesn't parse.
+func validateFormat(format Format, rendered []byte) error {
+	var decodeErr error
+
+	switch format {
+	case FormatNone:
+		return nil
+	case FormatTOML:
+		var out map[string]interface{}
+		_, decodeErr = toml.Decode(string(rendered), &out)
+	case FormatYAML:
+		var out map[string]interface{}
+		decodeErr = yaml.Unmarshal(rendered, &out)
+	case FormatJSON:
+		var out map[string]interface{}
+		decodeErr = json.Unmarshal(rendered, &out)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+
+	if decodeErr == nil {
+		return nil
+	}
+
+	line, ok := lineFromDecodeError(format, decodeErr, rendered)
+	if !ok {
+		return fmt.Errorf("invalid %s output: %s", format, decodeErr)
+	}
+
+	return fmt.Errorf("invalid %s output: %s\n%s", format, decodeErr, contextAround(rendered, line))
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// lineFromDecodeError extracts the 1-indexed line number a decoder reported
+// its error against, where the underlying library makes that available.
+func lineFromDecodeError(format Format, err error, rendered []byte) (int, bool) {
+	switch format {
+	case FormatTOML:
+		if perr, ok := err.(toml.ParseError); ok {
+			return perr.Position.Line, true
+		}
+	case FormatYAML:
+		if match := yamlLineRe.FindStringSubmatch(err.Error()); match != nil {
+			if line, convErr := strconv.Atoi(match[1]); convErr == nil {
+				return line, true
+			}
+		}
+	case FormatJSON:
+		if serr, ok := err.(*json.SyntaxError); ok {
+			return lineFromOffset(rendered, serr.Offset), true
+		}
+	}
+
+	return 0, false
+}
+
+// lineFromOffset converts a byte offset into rendered into a 1-indexed line
+// number.
+func lineFromOffset(rendered []byte, offset int64) int {
+	if offset < 0 || offset > int64(len(rendered)) {
+		offset = int64(len(rendered))
+	}
+	return bytes.Count(rendered[:offset], []byte("\n")) + 1
+}
+
+// contextAround returns up to two lines of rendered before and after line
+// (1-indexed), each prefixed with its line number, for inclusion in an
+// error message.
+func contextAround(rendered []byte, line int) string {
+	lines := strings.Split(string(rendered), "\n")
+
+	start := line - 3
+	if start < 0 {
+		start = 0
+	}
+	end := line + 2
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var buf bytes.Buffer
+	for i := start; i < end; i++ {
+		fmt.Fprintf(&buf, "%4d | %s\n", i+1, lines[i])
+	}
+
+	return strings.TrimRight(buf.String(), "\n")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecute_withFormatJSON_valid(t *testing.T) {
+	inTemplate := createTempfile([]byte(`{"maxconns": {{ key "service/redis/maxconns" }}}`), t)
+	defer deleteTempfile(inTemplate, t)
+
+	tmpl, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		Keys: map[string]string{"service/redis/maxconns": "3"},
+	}
+
+	if _, err := tmpl.Execute(context, WithFormat(FormatJSON)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecute_withFormatJSON_invalid(t *testing.T) {
+	inTemplate := createTempfile([]byte("{\n  \"maxconns\": ,\n}"), t)
+	defer deleteTempfile(inTemplate, t)
+
+	tmpl, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tmpl.Execute(&TemplateContext{}, WithFormat(FormatJSON))
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	if !strings.Contains(err.Error(), "invalid JSON output") {
+		t.Errorf("expected %q to contain %q", err.Error(), "invalid JSON output")
+	}
+}
+
+func TestExecute_withFormatTOML_invalid(t *testing.T) {
+	inTemplate := createTempfile([]byte("maxconns = \n"), t)
+	defer deleteTempfile(inTemplate, t)
+
+	tmpl, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tmpl.Execute(&TemplateContext{}, WithFormat(FormatTOML))
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	if !strings.Contains(err.Error(), "invalid TOML output") {
+		t.Errorf("expected %q to contain %q", err.Error(), "invalid TOML output")
+	}
+}
+
+func TestExecute_withFormatYAML_invalid(t *testing.T) {
+	inTemplate := createTempfile([]byte("maxconns: [1, 2\n"), t)
+	defer deleteTempfile(inTemplate, t)
+
+	tmpl, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tmpl.Execute(&TemplateContext{}, WithFormat(FormatYAML))
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	if !strings.Contains(err.Error(), "invalid YAML output") {
+		t.Errorf("expected %q to contain %q", err.Error(), "invalid YAML output")
+	}
+}
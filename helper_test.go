@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// createTempfile creates a temporary file with the given contents for use
+// in a test, failing the test immediately if the file cannot be created.
+func createTempfile(contents []byte, t *testing.T) *os.File {
+	f, err := ioutil.TempFile(os.TempDir(), "consul-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(contents) > 0 {
+		if _, err := f.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return f
+}
+
+// deleteTempfile removes a file created with createTempfile.
+func deleteTempfile(f *os.File, t *testing.T) {
+	if err := os.Remove(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+}
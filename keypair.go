@@ -0,0 +1,8 @@
+package main
+
+// KeyPair is a simple Key-Value pair, as returned by the Consul KV store for
+// a keyPrefix lookup.
+type KeyPair struct {
+	Key   string
+	Value string
+}
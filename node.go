@@ -0,0 +1,11 @@
+package main
+
+// Node represents a single node from the Consul catalog. Services lists the
+// names of the services registered on it, populated when the node was
+// fetched via the `node` template function (a single `nodes` listing does
+// not include it).
+type Node struct {
+	Node     string
+	Address  string
+	Services []string
+}
@@ -0,0 +1,37 @@
+package main
+
+// Service represents a single service instance as returned from the Consul
+// catalog/health API.
+type Service struct {
+	Node    string
+	Address string
+	ID      string
+	Name    string
+	Tag     string
+	Tags    []string
+	Port    uint64
+	Status  string
+}
+
+// ServiceList is a sortable slice of Service pointers. Sorting a ServiceList
+// gives callers (and rendered templates) deterministic output regardless of
+// the order Consul happens to return results in.
+type ServiceList []*Service
+
+func (s ServiceList) Len() int {
+	return len(s)
+}
+
+func (s ServiceList) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s ServiceList) Less(i, j int) bool {
+	if s[i].Node != s[j].Node {
+		return s[i].Node < s[j].Node
+	}
+	if s[i].ID != s[j].ID {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Port < s[j].Port
+}
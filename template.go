@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"text/template"
+)
+
+// Template represents a single template on disk, or a set of a root
+// template plus partials, along with the Dependencies it discovered the
+// first time it was parsed.
+type Template struct {
+	// path is the path to the root template on disk, used for HashCode and
+	// for reading the root's contents.
+	path string
+
+	// partials are the paths to any partial templates that are parsed
+	// alongside the root template and made available to it via
+	// `{{ template "name" . }}`.
+	partials []string
+
+	// extraFuncs are caller-registered functions (beyond the built-in
+	// service/key/keyPrefix/nodes/node/datacenters/byTag/healthy) that are
+	// made available to the template.
+	extraFuncs template.FuncMap
+
+	// tmpl is the parsed template tree (root + partials), kept around so
+	// Execute can re-run it against a real TemplateContext.
+	tmpl *template.Template
+
+	// rootName is the name the root template was registered under inside
+	// tmpl.
+	rootName string
+
+	// dependencies is populated during parsing and returned by
+	// Dependencies().
+	dependencies []Dependency
+}
+
+// NewTemplate creates and parses a new single-file Template at the given
+// path.
+func NewTemplate(path string) (*Template, error) {
+	return NewTemplateSet(path, nil, nil)
+}
+
+// NewTemplateSet creates and parses a new Template from a root template
+// plus a set of named partials, with extra registered as additional
+// functions available to both.
+func NewTemplateSet(root string, partials []string, extra template.FuncMap) (*Template, error) {
+	return newTemplateSet(root, partials, extra, "out")
+}
+
+// newTemplateSet is the unexported constructor backing NewTemplateSet. name
+// is the identifier the parsed root template is registered under; errors
+// reported by the stdlib text/template package are qualified with it, so
+// callers that know a more useful name (e.g. a TemplateBundle file's path)
+// can pass it through for file:line-qualified errors.
+func newTemplateSet(root string, partials []string, extra template.FuncMap, name string) (*Template, error) {
+	contents, err := ioutil.ReadFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		path:       root,
+		partials:   partials,
+		extraFuncs: extra,
+	}
+
+	if err := t.init(string(contents), name); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// init parses the root template and any partials, running a dependency-scan
+// pass over every parsed tree so that Dependencies() reflects calls made
+// from included partials as well as the root.
+func (t *Template) init(rootContents string, rootName string) error {
+	var deps []Dependency
+	seen := make(map[string]struct{})
+
+	record := func(d Dependency) error {
+		if _, ok := seen[d.HashCode()]; !ok {
+			seen[d.HashCode()] = struct{}{}
+			deps = append(deps, d)
+		}
+		return nil
+	}
+
+	funcs := scanFuncMap(record)
+	for name, fn := range t.extraFuncs {
+		funcs[name] = stubFunc(fn)
+	}
+
+	tmpl, err := template.New(rootName).Funcs(funcs).Parse(rootContents)
+	if err != nil {
+		return err
+	}
+
+	for _, partial := range t.partials {
+		contents, err := ioutil.ReadFile(partial)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tmpl.New(filepath.Base(partial)).Parse(string(contents)); err != nil {
+			return err
+		}
+	}
+
+	for _, named := range tmpl.Templates() {
+		if named.Tree == nil {
+			continue
+		}
+		if err := named.Execute(ioutil.Discard, nil); err != nil {
+			return err
+		}
+	}
+
+	t.tmpl = tmpl
+	t.rootName = rootName
+	t.dependencies = deps
+
+	return nil
+}
+
+// stubFunc builds a no-op replacement for fn that has the exact same
+// signature, so registering it in the scan-pass FuncMap can never panic or
+// fail a type check, no matter what the caller's function actually does.
+func stubFunc(fn interface{}) interface{} {
+	typ := reflect.TypeOf(fn)
+
+	stub := reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, typ.NumOut())
+		for i := 0; i < typ.NumOut(); i++ {
+			out[i] = reflect.Zero(typ.Out(i))
+		}
+		return out
+	})
+
+	return stub.Interface()
+}
+
+// scanFuncMap returns the FuncMap used during the dependency-scan pass.
+// Every function records the Dependency it represents via record and
+// returns a zero value cheap enough to range over, so the template body can
+// be "executed" once against nil data purely to discover dependencies.
+func scanFuncMap(record func(Dependency) error) template.FuncMap {
+	funcs := template.FuncMap{
+		"service": func(s string) ([]*Service, error) {
+			d, err := ParseServiceDependency(s)
+			if err != nil {
+				return nil, fmt.Errorf("error calling service: %s", err)
+			}
+			if err := record(d); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+		"key": func(s string) (string, error) {
+			if err := record(&KeyDependency{rawKey: s}); err != nil {
+				return "", err
+			}
+			return "", nil
+		},
+		"keyPrefix": func(s string) ([]*KeyPair, error) {
+			if err := record(&KeyPrefixDependency{rawKey: s}); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+		"nodes": func(dc ...string) ([]*Node, error) {
+			if err := record(&NodesDependency{rawKey: datacenterArg(dc)}); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+		"node": func(name string) (*Node, error) {
+			if err := record(&NodeDependency{rawKey: name}); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+		"datacenters": func() ([]string, error) {
+			if err := record(&DatacentersDependency{}); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	}
+
+	for name, fn := range filterFuncMap() {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
+// datacenterArg returns the single optional datacenter argument passed to
+// `nodes`, or "" if none was given.
+func datacenterArg(dc []string) string {
+	if len(dc) > 0 {
+		return dc[0]
+	}
+	return ""
+}
+
+// execFuncMap returns the FuncMap used to actually render a template against
+// a resolved TemplateContext, erroring if the context is missing data the
+// scan pass discovered it needed. The exception is `datacenters`, which never
+// errors: a context without Datacenters renders as an empty list.
+func execFuncMap(c *TemplateContext) template.FuncMap {
+	funcs := template.FuncMap{
+		"service": func(s string) ([]*Service, error) {
+			d, err := ParseServiceDependency(s)
+			if err != nil {
+				return nil, fmt.Errorf("error calling service: %s", err)
+			}
+			result, ok := c.Services[d.Key()]
+			if !ok {
+				return nil, fmt.Errorf("templateContext missing service `%s'", d.Key())
+			}
+			return result, nil
+		},
+		"key": func(s string) (string, error) {
+			result, ok := c.Keys[s]
+			if !ok {
+				return "", fmt.Errorf("templateContext missing key `%s'", s)
+			}
+			return result, nil
+		},
+		"keyPrefix": func(s string) ([]*KeyPair, error) {
+			result, ok := c.KeyPrefixes[s]
+			if !ok {
+				return nil, fmt.Errorf("templateContext missing keyPrefix `%s'", s)
+			}
+			return result, nil
+		},
+		"nodes": func(dc ...string) ([]*Node, error) {
+			key := datacenterArg(dc)
+			result, ok := c.Nodes[key]
+			if !ok {
+				return nil, fmt.Errorf("templateContext missing nodes `%s'", key)
+			}
+			return result, nil
+		},
+		"node": func(name string) (*Node, error) {
+			result, ok := c.NodeDetails[name]
+			if !ok {
+				return nil, fmt.Errorf("templateContext missing node `%s'", name)
+			}
+			return result, nil
+		},
+		"datacenters": func() ([]string, error) {
+			return c.Datacenters, nil
+		},
+	}
+
+	for name, fn := range filterFuncMap() {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
+// filterFuncMap returns pure post-filter helpers that operate on an already
+// fetched []*Service. They never add a Dependency, so they behave the same
+// way in both the scan and exec passes.
+func filterFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"byTag": func(tag string, services []*Service) []*Service {
+			var filtered []*Service
+			for _, s := range services {
+				for _, t := range s.Tags {
+					if t == tag {
+						filtered = append(filtered, s)
+						break
+					}
+				}
+			}
+			return filtered
+		},
+		"healthy": func(services []*Service) []*Service {
+			var filtered []*Service
+			for _, s := range services {
+				if s.Status == "" || s.Status == "passing" {
+					filtered = append(filtered, s)
+				}
+			}
+			return filtered
+		},
+	}
+}
+
+// Dependencies returns the full, deduplicated list of Dependencies this
+// Template (root and any partials) discovered while parsing.
+func (t *Template) Dependencies() []Dependency {
+	return t.dependencies
+}
+
+// Execute renders the Template against the given TemplateContext. By
+// default the rendered bytes are returned as-is; pass WithFormat to also
+// validate them as TOML/YAML/JSON immediately after rendering.
+func (t *Template) Execute(c *TemplateContext, opts ...ExecuteOption) ([]byte, error) {
+	if c == nil {
+		return nil, fmt.Errorf("templateContext must be given")
+	}
+
+	options := &executeOptions{format: FormatNone}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	funcs := execFuncMap(c)
+	for name, fn := range t.extraFuncs {
+		funcs[name] = fn
+	}
+
+	tmpl := t.tmpl.Funcs(funcs)
+
+	var buff bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buff, t.rootName, nil); err != nil {
+		return nil, err
+	}
+
+	rendered := buff.Bytes()
+
+	if err := validateFormat(options.format, rendered); err != nil {
+		return nil, err
+	}
+
+	return rendered, nil
+}
+
+// HashCode returns a unique identifier for this Template, suitable for
+// deduplicating Templates that point at the same file.
+func (t *Template) HashCode() string {
+	return fmt.Sprintf("Template|%s", t.path)
+}
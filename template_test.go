@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"text/template"
 )
 
 func TestDependencies_empty(t *testing.T) {
@@ -331,6 +332,182 @@ func TestExecute_rendersKeyPrefixes(t *testing.T) {
 	}
 }
 
+func TestDependencies_nodeFuncs(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range nodes }}{{ end }}
+    {{ with node "nyc-worker-1" }}{{ end }}
+    {{ range datacenters }}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependencies := template.Dependencies()
+
+	if num := len(dependencies); num != 3 {
+		t.Fatalf("expected 3 dependencies, got: %d", num)
+	}
+}
+
+func TestDependencies_nodeFuncsDuplicates(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range nodes }}{{ end }}
+    {{ range nodes }}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependencies := template.Dependencies()
+
+	if num := len(dependencies); num != 1 {
+		t.Fatalf("expected 1 Dependency, got: %d", num)
+	}
+}
+
+func TestExecute_missingNodes(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range nodes }}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, executeErr := template.Execute(&TemplateContext{})
+	if executeErr == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	expected := "templateContext missing nodes `'"
+	if !strings.Contains(executeErr.Error(), expected) {
+		t.Errorf("expected %q to contain %q", executeErr.Error(), expected)
+	}
+}
+
+func TestExecute_missingNode(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ with node "nyc-worker-1" }}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, executeErr := template.Execute(&TemplateContext{})
+	if executeErr == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	expected := "templateContext missing node `nyc-worker-1'"
+	if !strings.Contains(executeErr.Error(), expected) {
+		t.Errorf("expected %q to contain %q", executeErr.Error(), expected)
+	}
+}
+
+func TestExecute_rendersNodes(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range nodes }}
+    {{.Node}} {{.Address}}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		Nodes: map[string][]*Node{
+			"": []*Node{
+				&Node{Node: "nyc-worker-1", Address: "123.123.123.123"},
+			},
+		},
+	}
+
+	contents, err := template.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bytes.TrimSpace([]byte(`
+    nyc-worker-1 123.123.123.123
+  `))
+	if !bytes.Equal(bytes.TrimSpace(contents), expected) {
+		t.Errorf("expected \n%q\n to equal \n%q\n", bytes.TrimSpace(contents), expected)
+	}
+}
+
+func TestExecute_rendersNode(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ with node "nyc-worker-1" }}{{.Node}} serves {{ len .Services }} services{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		NodeDetails: map[string]*Node{
+			"nyc-worker-1": &Node{
+				Node:     "nyc-worker-1",
+				Address:  "123.123.123.123",
+				Services: []string{"web1", "web2"},
+			},
+		},
+	}
+
+	contents, err := template.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bytes.TrimSpace([]byte(`
+    nyc-worker-1 serves 2 services
+  `))
+	if !bytes.Equal(bytes.TrimSpace(contents), expected) {
+		t.Errorf("expected \n%q\n to equal \n%q\n", bytes.TrimSpace(contents), expected)
+	}
+}
+
+func TestExecute_rendersDatacenters(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range datacenters }}{{.}} {{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		Datacenters: []string{"nyc1", "sfo1"},
+	}
+
+	contents, err := template.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bytes.TrimSpace([]byte(`
+    nyc1 sfo1
+  `))
+	if !bytes.Equal(bytes.TrimSpace(contents), expected) {
+		t.Errorf("expected \n%q\n to equal \n%q\n", bytes.TrimSpace(contents), expected)
+	}
+}
+
 func TestHashCode_returnsValue(t *testing.T) {
 	template := &Template{path: "/foo/bar/blitz.ctmpl"}
 
@@ -340,6 +517,84 @@ func TestHashCode_returnsValue(t *testing.T) {
 	}
 }
 
+func TestNewTemplateSet_rendersPartials(t *testing.T) {
+	partial := createTempfile([]byte(`
+    {{ define "upstream" }}upstream {{ key "service/redis/host" }}{{ end }}
+  `), t)
+	defer deleteTempfile(partial, t)
+
+	root := createTempfile([]byte(`
+    {{ template "upstream" . }}
+  `), t)
+	defer deleteTempfile(root, t)
+
+	tmpl, err := NewTemplateSet(root.Name(), []string{partial.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		Keys: map[string]string{
+			"service/redis/host": "10.0.0.1",
+		},
+	}
+
+	contents, err := tmpl.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "upstream 10.0.0.1"
+	if !strings.Contains(string(contents), expected) {
+		t.Errorf("expected \n%q\n to contain \n%q\n", contents, expected)
+	}
+}
+
+func TestNewTemplateSet_dependenciesFromPartial(t *testing.T) {
+	partial := createTempfile([]byte(`
+    {{ define "upstream" }}{{ key "service/redis/host" }}{{ end }}
+  `), t)
+	defer deleteTempfile(partial, t)
+
+	root := createTempfile(nil, t)
+	defer deleteTempfile(root, t)
+
+	tmpl, err := NewTemplateSet(root.Name(), []string{partial.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dependencies := tmpl.Dependencies()
+	if num := len(dependencies); num != 1 {
+		t.Fatalf("expected 1 Dependency, got: %d", num)
+	}
+}
+
+func TestNewTemplateSet_extraFuncs(t *testing.T) {
+	root := createTempfile([]byte(`{{ shout "hi" }}`), t)
+	defer deleteTempfile(root, t)
+
+	extra := template.FuncMap{
+		"shout": func(s string) string {
+			return strings.ToUpper(s)
+		},
+	}
+
+	tmpl, err := NewTemplateSet(root.Name(), nil, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := tmpl.Execute(&TemplateContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "HI" {
+		t.Errorf("expected %q to equal %q", contents, "HI")
+	}
+}
+
 func TestServiceList_sorts(t *testing.T) {
 	a := ServiceList{
 		&Service{Node: "frontend01", ID: "1"},
@@ -379,3 +634,58 @@ func TestServiceList_sorts(t *testing.T) {
 		t.Fatal("invalid sort")
 	}
 }
+
+func TestServiceList_sortsTiebreaksOnIDThenPort(t *testing.T) {
+	a := ServiceList{
+		&Service{Node: "frontend01", ID: "2", Port: 9090},
+		&Service{Node: "frontend01", ID: "1", Port: 8081},
+		&Service{Node: "frontend01", ID: "1", Port: 8080},
+	}
+
+	sort.Sort(a)
+
+	expected := ServiceList{
+		&Service{Node: "frontend01", ID: "1", Port: 8080},
+		&Service{Node: "frontend01", ID: "1", Port: 8081},
+		&Service{Node: "frontend01", ID: "2", Port: 9090},
+	}
+
+	if !reflect.DeepEqual(a, expected) {
+		t.Fatal("invalid sort")
+	}
+}
+
+func TestExecute_rendersByTagAndHealthy(t *testing.T) {
+	inTemplate := createTempfile([]byte(`
+    {{ range service "web" | byTag "canary" | healthy }}
+    {{.Name}}{{ end }}
+  `), t)
+	defer deleteTempfile(inTemplate, t)
+
+	template, err := NewTemplate(inTemplate.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := &TemplateContext{
+		Services: map[string][]*Service{
+			"web": []*Service{
+				&Service{Name: "web1", Tags: []string{"canary"}, Status: "passing"},
+				&Service{Name: "web2", Tags: []string{"canary"}, Status: "critical"},
+				&Service{Name: "web3", Tags: []string{"stable"}, Status: "passing"},
+			},
+		},
+	}
+
+	contents, err := template.Execute(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bytes.TrimSpace([]byte(`
+    web1
+  `))
+	if !bytes.Equal(bytes.TrimSpace(contents), expected) {
+		t.Errorf("expected \n%q\n to equal \n%q\n", bytes.TrimSpace(contents), expected)
+	}
+}